@@ -0,0 +1,332 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ttrss/jsonx"
+)
+
+// View modes accepted by HeadlineOptions.ViewMode.
+const (
+	VIEW_ALL_ARTICLES = "all_articles"
+	VIEW_UNREAD       = "unread"
+	VIEW_ADAPTIVE     = "adaptive"
+	VIEW_MARKED       = "marked"
+	VIEW_UPDATED      = "updated"
+)
+
+// Article represents a single article/headline as returned by getHeadlines
+// or getArticle. Several fields use the jsonx Flex* types because TTRSS
+// sometimes encodes them as JSON numbers/booleans and sometimes as quoted
+// strings.
+type Article struct {
+	Id        jsonx.FlexInt
+	Title     string
+	Link      string
+	FeedID    jsonx.FlexInt `json:"feed_id"`
+	Unread    jsonx.FlexBool
+	Marked    jsonx.FlexBool
+	Published jsonx.FlexBool
+	Author    string
+	Content   string
+	Updated   jsonx.FlexTime
+	Labels    []ArticleLabel
+}
+
+// ArticleLabel is a label attached to an Article. TTRSS encodes each label
+// as a 4-element JSON array of [id, caption, fgColor, bgColor] rather than
+// an object, hence the custom UnmarshalJSON below.
+type ArticleLabel struct {
+	ID      jsonx.FlexInt
+	Caption string
+	FgColor string
+	BgColor string
+}
+
+func (l *ArticleLabel) UnmarshalJSON(data []byte) error {
+	var fields [4]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("ArticleLabel: %v", err)
+	}
+
+	id, err := asInt64(fields[0])
+	if err != nil {
+		return fmt.Errorf("ArticleLabel: id field: %v", err)
+	}
+	l.ID = jsonx.FlexInt(id)
+
+	caption, _ := fields[1].(string)
+	l.Caption = caption
+	fg, _ := fields[2].(string)
+	l.FgColor = fg
+	bg, _ := fields[3].(string)
+	l.BgColor = bg
+	return nil
+}
+
+// HeadlineOptions controls the getHeadlines request built by GetHeadlines.
+type HeadlineOptions struct {
+	// FeedID accepts either a real feed ID or one of the FEED_* constants.
+	FeedID int
+	// IsCat says FeedID names a category rather than a feed.
+	IsCat bool
+	// ViewMode is one of the VIEW_* constants; empty means the API default.
+	ViewMode           string
+	Limit              int
+	Skip               int
+	SinceID            int
+	Search             string
+	OrderBy            string
+	IncludeAttachments bool
+}
+
+// GetHeadlines fetches a page of headlines matching opts.
+func (tt *Client) GetHeadlines(opts HeadlineOptions) (articles []Article, err error) {
+	body := map[string]interface{}{
+		"feed_id": opts.FeedID,
+		"is_cat":  opts.IsCat,
+	}
+	if opts.ViewMode != "" {
+		body["view_mode"] = opts.ViewMode
+	}
+	if opts.Limit != 0 {
+		body["limit"] = opts.Limit
+	}
+	if opts.Skip != 0 {
+		body["skip"] = opts.Skip
+	}
+	if opts.SinceID != 0 {
+		body["since_id"] = opts.SinceID
+	}
+	if opts.Search != "" {
+		body["search"] = opts.Search
+	}
+	if opts.OrderBy != "" {
+		body["order_by"] = opts.OrderBy
+	}
+	if opts.IncludeAttachments {
+		body["include_attachments"] = true
+	}
+
+	resp, err := tt.Call("getHeadlines", body)
+	if err != nil {
+		return
+	}
+	if resp.Error != nil {
+		err = fmt.Errorf("API error: %s", resp.Error)
+		return
+	}
+
+	items, err := resp.GetContentSlice()
+	if err != nil {
+		err = fmt.Errorf("getHeadlines: %v", err)
+		return
+	}
+
+	raw, err := json.Marshal(items)
+	if err != nil {
+		err = fmt.Errorf("getHeadlines: re-marshalling content: %v", err)
+		return
+	}
+	err = json.Unmarshal(raw, &articles)
+	if err != nil {
+		err = fmt.Errorf("getHeadlines: decoding headlines: %v", err)
+		return
+	}
+	return
+}
+
+// GetArticle fetches a single article by ID.
+func (tt *Client) GetArticle(id int) (article Article, err error) {
+	resp, err := tt.Call("getArticle", map[string]interface{}{
+		"article_id": id,
+	})
+	if err != nil {
+		return
+	}
+	if resp.Error != nil {
+		err = fmt.Errorf("API error: %s", resp.Error)
+		return
+	}
+
+	items, err := resp.GetContentSlice()
+	if err != nil {
+		err = fmt.Errorf("getArticle: %v", err)
+		return
+	}
+	if len(items) == 0 {
+		err = fmt.Errorf("getArticle: no article found with id %d", id)
+		return
+	}
+
+	raw, err := json.Marshal(items[0])
+	if err != nil {
+		err = fmt.Errorf("getArticle: re-marshalling content: %v", err)
+		return
+	}
+	if err = json.Unmarshal(raw, &article); err != nil {
+		err = fmt.Errorf("getArticle: decoding article: %v", err)
+	}
+	return
+}
+
+// ArticleField names the article attribute an ArticleStateChange targets.
+type ArticleField int
+
+const (
+	FIELD_STARRED ArticleField = iota
+	FIELD_PUBLISHED
+	FIELD_UNREAD
+	FIELD_NOTE
+)
+
+// ArticleChangeMode says how an ArticleStateChange applies to its Field.
+type ArticleChangeMode int
+
+const (
+	MODE_UNSET ArticleChangeMode = iota
+	MODE_SET
+	MODE_TOGGLE
+)
+
+// ArticleStateChange describes a single updateArticle mutation. Use
+// NoteChange to build the special case of setting a note string.
+type ArticleStateChange struct {
+	Field ArticleField
+	Mode  ArticleChangeMode
+	// Note holds the text to store when Field == FIELD_NOTE.
+	Note string
+}
+
+// NoteChange builds the ArticleStateChange that sets an article's note to
+// text, encoded the way the API expects: field=FIELD_NOTE with mode 3 and
+// the text carried in "data".
+func NoteChange(text string) ArticleStateChange {
+	return ArticleStateChange{Field: FIELD_NOTE, Note: text}
+}
+
+// ArticleFeedSelector scopes an ArticleSelector to all articles in a feed or
+// category, optionally filtered further.
+type ArticleFeedSelector struct {
+	FeedID     int
+	CategoryID int
+	// BeforeDate, if non-zero, restricts the selection to articles updated
+	// before this Unix timestamp.
+	BeforeDate int64
+	// BeforeID, if non-zero, restricts the selection to articles with ID
+	// less than this value.
+	BeforeID   int
+	UnreadOnly bool
+}
+
+// ArticleSelector names which articles an UpdateArticles call applies to.
+// Exactly one of ByIDs or ByFeed should be set.
+type ArticleSelector struct {
+	ByIDs  []int
+	ByFeed *ArticleFeedSelector
+}
+
+// defaultHeadlinePageSize is the page size resolveIDs requests per
+// GetHeadlines call; TTRSS caps getHeadlines results at this many rows
+// regardless of the requested limit, so a selector spanning more articles
+// than this needs multiple requests to see them all.
+const defaultHeadlinePageSize = 200
+
+// resolveIDs turns sel into the concrete article IDs updateArticle needs,
+// fetching the matching headlines first when sel is scoped ByFeed.
+func (sel ArticleSelector) resolveIDs(tt *Client) (ids []int, err error) {
+	if len(sel.ByIDs) > 0 {
+		ids = sel.ByIDs
+		return
+	}
+	if sel.ByFeed == nil {
+		err = fmt.Errorf("ArticleSelector: one of ByIDs or ByFeed must be set")
+		return
+	}
+
+	opts := HeadlineOptions{
+		FeedID:   sel.ByFeed.FeedID,
+		ViewMode: VIEW_ALL_ARTICLES,
+		Limit:    defaultHeadlinePageSize,
+	}
+	if sel.ByFeed.CategoryID != 0 {
+		opts.FeedID = sel.ByFeed.CategoryID
+		opts.IsCat = true
+	}
+	if sel.ByFeed.UnreadOnly {
+		opts.ViewMode = VIEW_UNREAD
+	}
+
+	for {
+		headlines, hErr := tt.GetHeadlines(opts)
+		if hErr != nil {
+			err = hErr
+			return
+		}
+
+		for _, h := range headlines {
+			if sel.ByFeed.BeforeID != 0 && int(h.Id) >= sel.ByFeed.BeforeID {
+				continue
+			}
+			if sel.ByFeed.BeforeDate != 0 && h.Updated.Time().Unix() >= sel.ByFeed.BeforeDate {
+				continue
+			}
+			ids = append(ids, int(h.Id))
+		}
+
+		if len(headlines) < defaultHeadlinePageSize {
+			return
+		}
+		opts.Skip += defaultHeadlinePageSize
+	}
+}
+
+// UpdateArticles applies change to the articles named by sel, returning the
+// number of rows the API reports as updated.
+func (tt *Client) UpdateArticles(sel ArticleSelector, change ArticleStateChange) (updated int, err error) {
+	ids, err := sel.resolveIDs(tt)
+	if err != nil {
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	idStrs := make([]string, len(ids))
+	for i, id := range ids {
+		idStrs[i] = strconv.Itoa(id)
+	}
+
+	body := map[string]interface{}{
+		"article_ids": strings.Join(idStrs, ","),
+		"field":       int(change.Field),
+	}
+	if change.Field == FIELD_NOTE {
+		body["mode"] = 3
+		body["data"] = change.Note
+	} else {
+		body["mode"] = int(change.Mode)
+	}
+
+	resp, err := tt.Call("updateArticle", body)
+	if err != nil {
+		return
+	}
+	if resp.Error != nil {
+		err = fmt.Errorf("API error: %s", resp.Error)
+		return
+	}
+
+	n, err := resp.GetInt("updated")
+	if err != nil {
+		err = fmt.Errorf("updateArticle: %v", err)
+		return
+	}
+	updated = int(n)
+	return
+}
@@ -0,0 +1,148 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const fixtureHeadline = `{"id":"12","title":"Hello","link":"https://example.com/a",
+	"feed_id":"3","unread":true,"marked":false,"published":false,
+	"author":"someone","content":"body","updated":1690000000,
+	"labels":[[1,"Later","#fff","#000"]]}`
+
+func TestGetHeadlinesBuildsRequestAndDecodesArray(t *testing.T) {
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"seq":0,"status":0,"content":[` + fixtureHeadline + `]}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	articles, err := tt.GetHeadlines(HeadlineOptions{FeedID: 7, IsCat: true, Limit: 50})
+	if err != nil {
+		t.Fatalf("GetHeadlines: %v", err)
+	}
+
+	if gotBody["feed_id"] != float64(7) || gotBody["is_cat"] != true || gotBody["limit"] != float64(50) {
+		t.Errorf("got request body %+v", gotBody)
+	}
+
+	if len(articles) != 1 {
+		t.Fatalf("got %d articles, want 1", len(articles))
+	}
+	a := articles[0]
+	if a.Id != 12 || a.Title != "Hello" || a.FeedID != 3 || !bool(a.Unread) {
+		t.Errorf("got article %+v", a)
+	}
+	if len(a.Labels) != 1 || a.Labels[0].Caption != "Later" {
+		t.Errorf("got labels %+v", a.Labels)
+	}
+}
+
+func TestGetArticleCallsGetArticleOp(t *testing.T) {
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		if gotBody["op"] != "getArticle" {
+			t.Fatalf("got op %v, want getArticle", gotBody["op"])
+		}
+		w.Write([]byte(`{"seq":0,"status":0,"content":[` + fixtureHeadline + `]}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	article, err := tt.GetArticle(12)
+	if err != nil {
+		t.Fatalf("GetArticle: %v", err)
+	}
+	if gotBody["article_id"] != float64(12) {
+		t.Errorf("got article_id %v, want 12", gotBody["article_id"])
+	}
+	if article.Id != 12 || article.Title != "Hello" {
+		t.Errorf("got article %+v", article)
+	}
+}
+
+func TestGetArticleErrorsWhenNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"seq":0,"status":0,"content":[]}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	if _, err := tt.GetArticle(99); err == nil {
+		t.Error("expected an error when getArticle returns no articles")
+	}
+}
+
+func TestUpdateArticlesSendsCommaJoinedArticleIDs(t *testing.T) {
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"seq":0,"status":0,"content":{"updated":2}}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	updated, err := tt.UpdateArticles(
+		ArticleSelector{ByIDs: []int{5, 17, 3}},
+		ArticleStateChange{Field: FIELD_UNREAD, Mode: MODE_SET},
+	)
+	if err != nil {
+		t.Fatalf("UpdateArticles: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("got updated %d, want 2", updated)
+	}
+
+	if gotBody["article_ids"] != "5,17,3" {
+		t.Errorf("got article_ids %#v, want comma-separated string \"5,17,3\"", gotBody["article_ids"])
+	}
+}
+
+func TestResolveIDsByFeedPaginatesUntilShortPage(t *testing.T) {
+	var pages int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		pages++
+
+		if asFloat(body["skip"]) == 0 {
+			headlines := make([]string, defaultHeadlinePageSize)
+			for i := range headlines {
+				headlines[i] = `{"id":` + strconv.Itoa(i+1) + `,"title":"x","feed_id":7}`
+			}
+			w.Write([]byte(`{"seq":0,"status":0,"content":[` + strings.Join(headlines, ",") + `]}`))
+			return
+		}
+		w.Write([]byte(`{"seq":0,"status":0,"content":[{"id":9999,"title":"last","feed_id":7}]}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	ids, err := ArticleSelector{ByFeed: &ArticleFeedSelector{FeedID: 7}}.resolveIDs(tt)
+	if err != nil {
+		t.Fatalf("resolveIDs: %v", err)
+	}
+	if pages != 2 {
+		t.Errorf("got %d pages fetched, want 2", pages)
+	}
+	if len(ids) != defaultHeadlinePageSize+1 {
+		t.Errorf("got %d ids, want %d", len(ids), defaultHeadlinePageSize+1)
+	}
+	if ids[len(ids)-1] != 9999 {
+		t.Errorf("got last id %d, want 9999", ids[len(ids)-1])
+	}
+}
+
+func asFloat(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
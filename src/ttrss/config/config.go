@@ -0,0 +1,195 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+// Package config loads ttrss.Client settings from a simple key=value file,
+// so that callers (notably CLIs) don't have to hardcode a ttrss.ConnInfo.
+package config
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"ttrss"
+)
+
+// Config holds the settings needed to build a ttrss.Client via
+// NewClientFromConfig.
+type Config struct {
+	HostURL            string
+	User               string
+	Password           string
+	SessionCachePath   string
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+	CategoryDefault    int
+}
+
+// IsValid reports whether cfg has enough information to log in.
+func (cfg *Config) IsValid() bool {
+	return cfg.HostURL != "" && cfg.User != ""
+}
+
+// Parse reads a simple key=value config file from r, one setting per line.
+// Blank lines and lines starting with "#" are ignored. Recognized keys are
+// ListenURL (alias for HostURL), User, Password, SessionCachePath,
+// InsecureSkipVerify, Timeout (a time.ParseDuration string), and
+// CategoryDefault (an integer, defaulting to ttrss.CATEGORY_UNCATEGORIZED).
+func Parse(r io.Reader) (cfg *Config, err error) {
+	cfg = &Config{CategoryDefault: ttrss.CATEGORY_UNCATEGORIZED}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			err = fmt.Errorf("config: line %d: expected key=value, got %q",
+				lineNo, line)
+			return
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "ListenURL", "HostURL":
+			cfg.HostURL = value
+		case "User":
+			cfg.User = value
+		case "Password":
+			cfg.Password = value
+		case "SessionCachePath":
+			cfg.SessionCachePath = value
+		case "InsecureSkipVerify":
+			cfg.InsecureSkipVerify, err = strconv.ParseBool(value)
+			if err != nil {
+				err = fmt.Errorf("config: line %d: InsecureSkipVerify: %v",
+					lineNo, err)
+				return
+			}
+		case "Timeout":
+			cfg.Timeout, err = time.ParseDuration(value)
+			if err != nil {
+				err = fmt.Errorf("config: line %d: Timeout: %v", lineNo, err)
+				return
+			}
+		case "CategoryDefault":
+			cfg.CategoryDefault, err = strconv.Atoi(value)
+			if err != nil {
+				err = fmt.Errorf("config: line %d: CategoryDefault: %v",
+					lineNo, err)
+				return
+			}
+		default:
+			err = fmt.Errorf("config: line %d: unknown key %q", lineNo, key)
+			return
+		}
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		err = fmt.Errorf("config: %v", scanErr)
+		return
+	}
+	return
+}
+
+// Load reads and parses the config file at path.
+func Load(path string) (cfg *Config, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		err = fmt.Errorf("config: opening %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// NewClientFromConfig builds a ttrss.Client from cfg, configuring its HTTP
+// transport (TLS options, timeout) and logging in. If cfg.SessionCachePath
+// is set, a cached SessionID is tried first via an isLoggedIn probe, and
+// re-used rather than logging in again; on probe failure (or no cache yet)
+// it logs in normally and writes the resulting SessionID back to the cache.
+func NewClientFromConfig(cfg *Config) (tt *ttrss.Client, err error) {
+	if !cfg.IsValid() {
+		err = fmt.Errorf("config: HostURL and User must be set")
+		return
+	}
+
+	tt = &ttrss.Client{
+		Timeout: cfg.Timeout,
+	}
+	tt.Client.Transport = &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify},
+	}
+
+	conn := ttrss.ConnInfo{
+		HostURL:  cfg.HostURL,
+		User:     cfg.User,
+		Password: cfg.Password,
+	}
+
+	if cfg.SessionCachePath != "" {
+		if sessionID, readErr := readSessionCache(cfg.SessionCachePath); readErr == nil && sessionID != "" {
+			apiEP := conn.HostURL
+			if !strings.HasSuffix(apiEP, "/") {
+				apiEP += "/"
+			}
+			tt.ApiEP = apiEP + "api/"
+			tt.SessionID = sessionID
+
+			if loggedIn, probeErr := isLoggedIn(tt); probeErr == nil && loggedIn {
+				return
+			}
+			tt.SessionID = ""
+		}
+	}
+
+	_, err = tt.Login(conn)
+	if err != nil {
+		return
+	}
+
+	if cfg.SessionCachePath != "" {
+		if cacheErr := writeSessionCache(cfg.SessionCachePath, tt.SessionID); cacheErr != nil {
+			err = fmt.Errorf("config: caching session: %v", cacheErr)
+			return
+		}
+	}
+	return
+}
+
+// isLoggedIn probes whether tt's current SessionID is still valid.
+func isLoggedIn(tt *ttrss.Client) (ok bool, err error) {
+	resp, err := tt.Call("isLoggedIn", map[string]interface{}{})
+	if err != nil {
+		return
+	}
+	if resp.Error != nil {
+		err = fmt.Errorf("API error: %s", resp.Error)
+		return
+	}
+	ok, _ = resp.Content["status"].(bool)
+	return
+}
+
+func readSessionCache(path string) (sessionID string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	sessionID = strings.TrimSpace(string(data))
+	return
+}
+
+func writeSessionCache(path string, sessionID string) error {
+	return os.WriteFile(path, []byte(sessionID), 0600)
+}
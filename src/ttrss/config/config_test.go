@@ -0,0 +1,131 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewClientFromConfigReusesCachedSession(t *testing.T) {
+	var loginCalls, probeCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body["op"] {
+		case "isLoggedIn":
+			probeCalls++
+			if body["sid"] != "cached-session" {
+				t.Errorf("got sid %v, want cached-session", body["sid"])
+			}
+			w.Write([]byte(`{"seq":0,"status":0,"content":{"status":true}}`))
+		case "login":
+			loginCalls++
+			w.Write([]byte(`{"seq":0,"status":0,"content":{"session_id":"fresh-session"}}`))
+		default:
+			t.Fatalf("unexpected op %v", body["op"])
+		}
+	}))
+	defer srv.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "session")
+	if err := os.WriteFile(cachePath, []byte("cached-session"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg := &Config{
+		HostURL:          srv.URL,
+		User:             "alice",
+		Password:         "hunter2",
+		SessionCachePath: cachePath,
+	}
+
+	tt, err := NewClientFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewClientFromConfig: %v", err)
+	}
+	if tt.SessionID != "cached-session" {
+		t.Errorf("got SessionID %q, want cached-session", tt.SessionID)
+	}
+	if probeCalls != 1 || loginCalls != 0 {
+		t.Errorf("got probeCalls=%d loginCalls=%d, want 1 and 0", probeCalls, loginCalls)
+	}
+}
+
+func TestNewClientFromConfigFallsBackToLoginWhenCacheIsStale(t *testing.T) {
+	var loginCalls, probeCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body["op"] {
+		case "isLoggedIn":
+			probeCalls++
+			w.Write([]byte(`{"seq":0,"status":0,"content":{"status":false}}`))
+		case "login":
+			loginCalls++
+			w.Write([]byte(`{"seq":0,"status":0,"content":{"session_id":"fresh-session"}}`))
+		default:
+			t.Fatalf("unexpected op %v", body["op"])
+		}
+	}))
+	defer srv.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "session")
+	if err := os.WriteFile(cachePath, []byte("stale-session"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg := &Config{
+		HostURL:          srv.URL,
+		User:             "alice",
+		Password:         "hunter2",
+		SessionCachePath: cachePath,
+	}
+
+	tt, err := NewClientFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewClientFromConfig: %v", err)
+	}
+	if tt.SessionID != "fresh-session" {
+		t.Errorf("got SessionID %q, want fresh-session", tt.SessionID)
+	}
+	if probeCalls != 1 || loginCalls != 1 {
+		t.Errorf("got probeCalls=%d loginCalls=%d, want 1 and 1", probeCalls, loginCalls)
+	}
+
+	cached, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(cached) != "fresh-session" {
+		t.Errorf("got cached session %q, want fresh-session to be written back", cached)
+	}
+}
+
+func TestNewClientFromConfigLogsInWithoutCache(t *testing.T) {
+	var loginCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["op"] != "login" {
+			t.Fatalf("unexpected op %v", body["op"])
+		}
+		loginCalls++
+		w.Write([]byte(`{"seq":0,"status":0,"content":{"session_id":"fresh-session"}}`))
+	}))
+	defer srv.Close()
+
+	cfg := &Config{HostURL: srv.URL, User: "alice", Password: "hunter2"}
+
+	tt, err := NewClientFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewClientFromConfig: %v", err)
+	}
+	if tt.SessionID != "fresh-session" || loginCalls != 1 {
+		t.Errorf("got SessionID=%q loginCalls=%d", tt.SessionID, loginCalls)
+	}
+}
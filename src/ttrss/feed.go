@@ -0,0 +1,116 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"ttrss/jsonx"
+)
+
+// Category represents a feed category as returned by getCategories.
+type Category struct {
+	Id     jsonx.FlexInt
+	Title  string
+	Unread jsonx.FlexInt
+}
+
+// GetCategories calls getCategories and returns the resulting categories.
+func (tt *Client) GetCategories(unreadOnly, enableNested, includeEmpty bool) (categories []Category, err error) {
+	body := map[string]interface{}{
+		"unread_only":   unreadOnly,
+		"enable_nested": enableNested,
+		"include_empty": includeEmpty,
+	}
+
+	resp, err := tt.Call("getCategories", body)
+	if err != nil {
+		return
+	}
+	if resp.Error != nil {
+		err = fmt.Errorf("API error: %s", resp.Error)
+		return
+	}
+
+	items, err := resp.GetContentSlice()
+	if err != nil {
+		err = fmt.Errorf("getCategories: %v", err)
+		return
+	}
+
+	raw, err := json.Marshal(items)
+	if err != nil {
+		err = fmt.Errorf("getCategories: re-marshalling content: %v", err)
+		return
+	}
+	if err = json.Unmarshal(raw, &categories); err != nil {
+		err = fmt.Errorf("getCategories: decoding categories: %v", err)
+	}
+	return
+}
+
+// Feed represents a single feed as returned by getFeeds.
+type Feed struct {
+	Id          jsonx.FlexInt
+	Title       string
+	FeedURL     string `json:"feed_url"`
+	Unread      jsonx.FlexInt
+	HasIcon     jsonx.FlexBool `json:"has_icon"`
+	CatID       jsonx.FlexInt  `json:"cat_id"`
+	LastUpdated jsonx.FlexTime `json:"last_updated"`
+	OrderID     jsonx.FlexInt  `json:"order_id"`
+}
+
+// FeedListOptions controls the getFeeds request built by GetFeeds.
+type FeedListOptions struct {
+	UnreadOnly    bool
+	Limit         int
+	Offset        int
+	IncludeNested bool
+}
+
+// GetFeeds calls getFeeds for catID (accepting the CATEGORY_* constants)
+// and returns the resulting feeds.
+func (tt *Client) GetFeeds(catID int, opts FeedListOptions) (feeds []Feed, err error) {
+	body := map[string]interface{}{
+		"cat_id": catID,
+	}
+	if opts.UnreadOnly {
+		body["unread_only"] = true
+	}
+	if opts.Limit != 0 {
+		body["limit"] = opts.Limit
+	}
+	if opts.Offset != 0 {
+		body["offset"] = opts.Offset
+	}
+	if opts.IncludeNested {
+		body["include_nested"] = true
+	}
+
+	resp, err := tt.Call("getFeeds", body)
+	if err != nil {
+		return
+	}
+	if resp.Error != nil {
+		err = fmt.Errorf("API error: %s", resp.Error)
+		return
+	}
+
+	items, err := resp.GetContentSlice()
+	if err != nil {
+		err = fmt.Errorf("getFeeds: %v", err)
+		return
+	}
+
+	raw, err := json.Marshal(items)
+	if err != nil {
+		err = fmt.Errorf("getFeeds: re-marshalling content: %v", err)
+		return
+	}
+	if err = json.Unmarshal(raw, &feeds); err != nil {
+		err = fmt.Errorf("getFeeds: decoding feeds: %v", err)
+	}
+	return
+}
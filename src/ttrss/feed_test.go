@@ -0,0 +1,69 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetCategoriesBuildsRequestAndDecodesArray(t *testing.T) {
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"seq":0,"status":0,"content":[{"id":"1","title":"News","unread":"4"}]}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	categories, err := tt.GetCategories(true, false, true)
+	if err != nil {
+		t.Fatalf("GetCategories: %v", err)
+	}
+
+	if gotBody["unread_only"] != true || gotBody["include_empty"] != true {
+		t.Errorf("got request body %+v", gotBody)
+	}
+	if len(categories) != 1 || categories[0].Id != 1 || categories[0].Title != "News" || categories[0].Unread != 4 {
+		t.Errorf("got categories %+v", categories)
+	}
+}
+
+func TestGetFeedsBuildsRequestAndDecodesArray(t *testing.T) {
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"seq":0,"status":0,"content":[
+			{"id":"9","title":"Example","feed_url":"https://example.com/feed.xml",
+			 "unread":"2","has_icon":true,"cat_id":"1","last_updated":1690000000,"order_id":"1"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	feeds, err := tt.GetFeeds(1, FeedListOptions{UnreadOnly: true, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetFeeds: %v", err)
+	}
+
+	if gotBody["cat_id"] != float64(1) || gotBody["unread_only"] != true || gotBody["limit"] != float64(10) {
+		t.Errorf("got request body %+v", gotBody)
+	}
+	if len(feeds) != 1 || feeds[0].Id != 9 || feeds[0].FeedURL != "https://example.com/feed.xml" {
+		t.Errorf("got feeds %+v", feeds)
+	}
+}
+
+func TestGetCategoriesReturnsAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"seq":0,"status":1,"content":{"error":"NOT_LOGGED_IN"}}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	if _, err := tt.GetCategories(false, false, false); err == nil {
+		t.Error("expected an error when the API reports an error status")
+	}
+}
@@ -0,0 +1,89 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+// Package jsonx provides JSON decoding helpers for TTRSS's API, which is
+// inconsistent about whether numeric and boolean fields (feed IDs, cat_id,
+// unread counts, timestamps) are encoded as native JSON types or as quoted
+// strings. The Flex* types below unmarshal either form.
+package jsonx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FlexInt decodes a JSON number or a quoted numeric string into an int.
+// An empty string decodes to zero.
+type FlexInt int
+
+func (v *FlexInt) UnmarshalJSON(data []byte) error {
+	n, err := parseFlexInt(data)
+	if err != nil {
+		return fmt.Errorf("jsonx.FlexInt: %v", err)
+	}
+	*v = FlexInt(n)
+	return nil
+}
+
+// FlexInt64 is FlexInt's int64 counterpart, for fields that may not fit in
+// an int (e.g. timestamps on 32-bit platforms).
+type FlexInt64 int64
+
+func (v *FlexInt64) UnmarshalJSON(data []byte) error {
+	n, err := parseFlexInt(data)
+	if err != nil {
+		return fmt.Errorf("jsonx.FlexInt64: %v", err)
+	}
+	*v = FlexInt64(n)
+	return nil
+}
+
+func parseFlexInt(data []byte) (int64, error) {
+	s := strings.Trim(string(data), `"`)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// FlexBool decodes a JSON bool, a JSON 0/1, or a quoted string form of
+// either into a bool. An empty string decodes to false.
+type FlexBool bool
+
+func (v *FlexBool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	switch s {
+	case "", "0", "false":
+		*v = false
+	case "1", "true":
+		*v = true
+	default:
+		return fmt.Errorf("jsonx.FlexBool: unrecognized value %q", data)
+	}
+	return nil
+}
+
+// FlexTime decodes a Unix timestamp, whether encoded as a JSON number or a
+// quoted numeric string, into a time.Time. An empty string decodes to the
+// zero time.
+type FlexTime time.Time
+
+func (v *FlexTime) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" {
+		*v = FlexTime(time.Time{})
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("jsonx.FlexTime: %v", err)
+	}
+	*v = FlexTime(time.Unix(n, 0))
+	return nil
+}
+
+// Time returns v as a time.Time.
+func (v FlexTime) Time() time.Time {
+	return time.Time(v)
+}
@@ -0,0 +1,80 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+// Package opml parses and serializes OPML 2.0 outline documents: nested
+// <outline> elements, where a feed outline carries type="rss" and xmlUrl,
+// and a category outline nests further outlines instead.
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Outline represents a single OPML <outline> element. A feed outline has
+// Type "rss" and a non-empty XMLURL; a category outline has neither and
+// nests further outlines in Outlines instead.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Login    string    `xml:"login,attr,omitempty"`
+	Password string    `xml:"password,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+type head struct {
+	Title string `xml:"title"`
+}
+
+type body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+type document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    head     `xml:"head"`
+	Body    body     `xml:"body"`
+}
+
+// Document is a parsed OPML outline tree.
+type Document struct {
+	Title    string
+	Outlines []Outline
+}
+
+// Parse parses an OPML 2.0 document from r.
+func Parse(r io.Reader) (*Document, error) {
+	var doc document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("opml: %v", err)
+	}
+	return &Document{
+		Title:    doc.Head.Title,
+		Outlines: doc.Body.Outlines,
+	}, nil
+}
+
+// Write serializes d as an OPML 2.0 document to w.
+func (d *Document) Write(w io.Writer) error {
+	doc := document{
+		Version: "2.0",
+		Head:    head{Title: d.Title},
+		Body:    body{Outlines: d.Outlines},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("opml: %v", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("opml: %v", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
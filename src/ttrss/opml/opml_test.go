@@ -0,0 +1,80 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package opml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const fixtureDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>feeds</title></head>
+  <body>
+    <outline text="News" title="News">
+      <outline text="Example" title="Example" type="rss" xmlUrl="https://example.com/feed.xml"/>
+    </outline>
+    <outline text="Top Level" title="Top Level" type="rss" xmlUrl="https://example.org/feed.xml"/>
+  </body>
+</opml>`
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(strings.NewReader(fixtureDocument))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if doc.Title != "feeds" {
+		t.Errorf("got Title %q, want %q", doc.Title, "feeds")
+	}
+	if len(doc.Outlines) != 2 {
+		t.Fatalf("got %d top-level outlines, want 2", len(doc.Outlines))
+	}
+
+	category := doc.Outlines[0]
+	if category.Text != "News" || category.Type != "" {
+		t.Errorf("got category outline %+v", category)
+	}
+	if len(category.Outlines) != 1 || category.Outlines[0].XMLURL != "https://example.com/feed.xml" {
+		t.Errorf("got category children %+v", category.Outlines)
+	}
+
+	feed := doc.Outlines[1]
+	if feed.Type != "rss" || feed.XMLURL != "https://example.org/feed.xml" {
+		t.Errorf("got top-level feed outline %+v", feed)
+	}
+}
+
+func TestWriteThenParseRoundTrips(t *testing.T) {
+	doc := &Document{
+		Title: "round trip",
+		Outlines: []Outline{
+			{
+				Text:  "Category",
+				Title: "Category",
+				Outlines: []Outline{
+					{Text: "Feed", Title: "Feed", Type: "rss", XMLURL: "https://example.com/a.xml"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse of written document: %v", err)
+	}
+	if got.Title != doc.Title {
+		t.Errorf("got Title %q, want %q", got.Title, doc.Title)
+	}
+	if len(got.Outlines) != 1 || len(got.Outlines[0].Outlines) != 1 {
+		t.Fatalf("got outlines %+v", got.Outlines)
+	}
+	if feed := got.Outlines[0].Outlines[0]; feed.XMLURL != "https://example.com/a.xml" {
+		t.Errorf("got feed outline %+v", feed)
+	}
+}
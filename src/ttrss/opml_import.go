@@ -0,0 +1,171 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"fmt"
+	"io"
+
+	"ttrss/opml"
+)
+
+// ImportOptions controls ImportOPML.
+type ImportOptions struct {
+	// DefaultCategoryID is used for feed outlines that appear outside any
+	// category outline. Defaults to CATEGORY_UNCATEGORIZED.
+	DefaultCategoryID int
+}
+
+// SubscribeFailure records a single feed's subscribeToFeed failure during
+// ImportOPML.
+type SubscribeFailure struct {
+	URL string
+	Err error
+}
+
+// ImportReport summarizes an ImportOPML run.
+type ImportReport struct {
+	Added    int
+	Skipped  int
+	Failed   int
+	Failures []SubscribeFailure
+}
+
+// ImportOPML parses the OPML document in r and subscribes to every feed
+// outline it contains, creating any category outline (via addCategory)
+// that doesn't already exist. Per-feed HTTP Basic credentials given as
+// login/password attributes on a feed outline are passed through to
+// Subscribe. Feeds rejected with a *SubscribeError are collected into the
+// returned report rather than aborting the import; any other error
+// (transport failure, API error, or a failed addCategory) aborts it.
+func (tt *Client) ImportOPML(r io.Reader, opts ImportOptions) (report ImportReport, err error) {
+	doc, err := opml.Parse(r)
+	if err != nil {
+		err = fmt.Errorf("ImportOPML: %v", err)
+		return
+	}
+
+	// categoryKey identifies a category by name within a specific parent, so
+	// that same-named categories nested under different parents (e.g. two
+	// "News" outlines under different top-level categories) don't collapse
+	// into a single TTRSS category.
+	type categoryKey struct {
+		parentID int
+		name     string
+	}
+	categoryIDs := map[categoryKey]int{}
+
+	var walk func(outlines []opml.Outline, categoryID int) error
+	walk = func(outlines []opml.Outline, categoryID int) error {
+		for _, o := range outlines {
+			if o.XMLURL != "" {
+				didSubscribe, subErr := tt.Subscribe(o.XMLURL, categoryID, o.Login, o.Password)
+				if subErr != nil {
+					if _, ok := subErr.(*SubscribeError); !ok {
+						return subErr
+					}
+					report.Failed++
+					report.Failures = append(report.Failures,
+						SubscribeFailure{URL: o.XMLURL, Err: subErr})
+					continue
+				}
+				if didSubscribe {
+					report.Added++
+				} else {
+					report.Skipped++
+				}
+				continue
+			}
+
+			name := o.Title
+			if name == "" {
+				name = o.Text
+			}
+			key := categoryKey{parentID: categoryID, name: name}
+			childCategoryID, ok := categoryIDs[key]
+			if !ok {
+				childCategoryID, err = tt.addCategory(name, categoryID)
+				if err != nil {
+					return err
+				}
+				categoryIDs[key] = childCategoryID
+			}
+			if err := walk(o.Outlines, childCategoryID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err = walk(doc.Outlines, opts.DefaultCategoryID)
+	return
+}
+
+// addCategory creates category name under parentID (0 for top-level) and
+// returns its ID.
+func (tt *Client) addCategory(name string, parentID int) (id int, err error) {
+	resp, err := tt.Call("addCategory", map[string]interface{}{
+		"category":  name,
+		"parent_id": parentID,
+	})
+	if err != nil {
+		return
+	}
+	if resp.Error != nil {
+		err = fmt.Errorf("API error: %s", resp.Error)
+		return
+	}
+
+	n, err := resp.GetInt("category_id")
+	if err != nil {
+		err = fmt.Errorf("addCategory: %v", err)
+		return
+	}
+	id = int(n)
+	return
+}
+
+// ExportOPML fetches the feed tree and writes it to w as an OPML 2.0
+// document, preserving category hierarchy.
+func (tt *Client) ExportOPML(w io.Writer) error {
+	tree, err := tt.GetFeedTree(true)
+	if err != nil {
+		return fmt.Errorf("ExportOPML: %v", err)
+	}
+
+	doc := &opml.Document{
+		Title:    "ttrss-tool feed export",
+		Outlines: childOutlines(&tree),
+	}
+	if err := doc.Write(w); err != nil {
+		return fmt.Errorf("ExportOPML: %v", err)
+	}
+	return nil
+}
+
+// childOutlines converts item's children into OPML outlines, recursing
+// through nested categories. WalkFeedTree's flat, single-callback shape has
+// no hook for "this category's children are done", which nested OPML
+// output needs, so this recurses over FeedTreeItem.Items directly instead.
+func childOutlines(item *FeedTreeItem) []opml.Outline {
+	outlines := make([]opml.Outline, 0, len(item.Items))
+	for i := range item.Items {
+		child := &item.Items[i]
+		switch child.Type {
+		case TypeFeed:
+			outlines = append(outlines, opml.Outline{
+				Text:   child.Name,
+				Title:  child.Name,
+				Type:   "rss",
+				XMLURL: child.FeedURL,
+			})
+		case TypeCategory:
+			outlines = append(outlines, opml.Outline{
+				Text:     child.Name,
+				Title:    child.Name,
+				Outlines: childOutlines(child),
+			})
+		}
+	}
+	return outlines
+}
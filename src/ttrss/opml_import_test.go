@@ -0,0 +1,81 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fixtureOPMLSameNameUnderDifferentParents nests two "News" category
+// outlines under different top-level categories, to exercise the
+// (parent, name) keying in ImportOPML.
+const fixtureOPMLSameNameUnderDifferentParents = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>feeds</title></head>
+  <body>
+    <outline text="Tech" title="Tech">
+      <outline text="News" title="News">
+        <outline text="A" title="A" type="rss" xmlUrl="https://example.com/a.xml"/>
+      </outline>
+    </outline>
+    <outline text="Sports" title="Sports">
+      <outline text="News" title="News">
+        <outline text="B" title="B" type="rss" xmlUrl="https://example.com/b.xml"/>
+      </outline>
+    </outline>
+  </body>
+</opml>`
+
+func TestImportOPMLKeysCategoriesByParent(t *testing.T) {
+	var mu sync.Mutex
+	var nextCategoryID int64 = 1
+	seenParents := map[int64]bool{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch body["op"] {
+		case "addCategory":
+			mu.Lock()
+			id := nextCategoryID
+			nextCategoryID++
+			parentID, _ := asInt64(body["parent_id"])
+			if body["category"] == "News" {
+				seenParents[parentID] = true
+			}
+			mu.Unlock()
+			w.Write([]byte(`{"seq":0,"status":0,"content":{"category_id":` +
+				strconv.FormatInt(id, 10) + `}}`))
+		case "subscribeToFeed":
+			w.Write([]byte(`{"seq":0,"status":0,"content":{"status":{"code":1,"message":""}}}`))
+		default:
+			t.Fatalf("unexpected op %v", body["op"])
+		}
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	report, err := tt.ImportOPML(strings.NewReader(fixtureOPMLSameNameUnderDifferentParents), ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportOPML: %v", err)
+	}
+	if report.Added != 2 {
+		t.Errorf("got Added = %d, want 2", report.Added)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if nextCategoryID-1 != 4 {
+		t.Errorf("got %d addCategory calls, want 4 (Tech, Sports, and two distinct News)", nextCategoryID-1)
+	}
+	if len(seenParents) < 2 {
+		t.Errorf("got parent_id values %v, want at least 2 distinct parents among the News categories", seenParents)
+	}
+}
@@ -4,12 +4,18 @@ package ttrss
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"path/filepath"
 	"fmt"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"ttrss/jsonx"
 )
 
 // Status values returned from an API request.
@@ -44,9 +50,67 @@ type Client struct {
 	ApiEP     string
 	Client    http.Client
 	SessionID string
+
+	// Timeout, if non-zero, bounds every call made through CallCtx (and
+	// therefore Call) with a derived context deadline on top of whatever
+	// deadline the caller's context already carries.
+	Timeout time.Duration
+
+	// RetryPolicy controls automatic retries of failed calls. The zero
+	// value disables retries (a single attempt is made).
+	RetryPolicy RetryPolicy
+}
+
+// RetryPolicy configures retries for network errors and 5xx responses.
+// API-level errors (API_STATUS_ERR) are never retried, since retrying them
+// would just repeat the same failure.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Zero or one means "no retries".
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry; it doubles on each
+	// subsequent attempt, capped at MaxDelay, and jittered by up to half
+	// of the computed delay. Zero selects a default of 250ms.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the computed backoff delay. Zero selects a default of
+	// 10s.
+	MaxDelay time.Duration
+}
+
+// retryableError marks an error from a single call attempt as safe to
+// retry: a transport-level failure or an HTTP 5xx response.
+type retryableError struct {
+	err error
+}
+
+func (r *retryableError) Error() string { return r.err.Error() }
+func (r *retryableError) Unwrap() error { return r.err }
+
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	base := policy.BaseDelay
+	if base <= 0 {
+		base = 250 * time.Millisecond
+	}
+	maxDelay := policy.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+
+	d := base * time.Duration(int64(1)<<uint(attempt-1))
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
 }
 
-// Resp represents the JSON response returned by the TTRSS API.
+// Resp represents the JSON response returned by the TTRSS API. Most ops'
+// "content" is a JSON object, decoded into Content; a few (getHeadlines,
+// getCategories, getFeeds) send a bare JSON array instead, decoded into
+// ContentArray. UnmarshalJSON below is what makes both possible, since a
+// plain struct tag can't express "this field is sometimes an object and
+// sometimes an array".
 type Resp struct {
 	// Same as request "seq" number, if provided.
 	// Otherwise mostly 0, but sometimes null.
@@ -58,18 +122,178 @@ type Resp struct {
 	// Content["error"] wrapped as an error; nil if not present or not string
 	Error error
 
-	// Content of the response.
+	// Content of the response, when it is a JSON object.
 	Content map[string]interface{}
+
+	// ContentArray holds the response, when its content is a bare JSON
+	// array instead of an object.
+	ContentArray []interface{}
+}
+
+// UnmarshalJSON decodes a Resp envelope, routing its "content" field to
+// either Content or ContentArray depending on whether the API sent an
+// object or an array. Numbers are decoded as json.Number (see asInt64)
+// rather than float64, since TTRSS sometimes sends numeric fields quoted.
+func (r *Resp) UnmarshalJSON(data []byte) error {
+	var envelope struct {
+		Seq     int             `json:"seq"`
+		Status  int             `json:"status"`
+		Content json.RawMessage `json:"content"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&envelope); err != nil {
+		return err
+	}
+	r.Seq = envelope.Seq
+	r.Status = envelope.Status
+
+	content := bytes.TrimSpace(envelope.Content)
+	if len(content) == 0 {
+		return nil
+	}
+
+	dec = json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	if content[0] == '[' {
+		return dec.Decode(&r.ContentArray)
+	}
+	return dec.Decode(&r.Content)
+}
+
+// GetContentSlice returns the response's content as a JSON array, for ops
+// (getHeadlines, getCategories, getFeeds) whose content is a bare array
+// rather than an object.
+func (r Resp) GetContentSlice() ([]interface{}, error) {
+	if r.ContentArray == nil {
+		return nil, fmt.Errorf("Resp: content is not a JSON array")
+	}
+	return r.ContentArray, nil
+}
+
+// GetMap returns r.Content[key] as a JSON object.
+func (r Resp) GetMap(key string) (map[string]interface{}, error) {
+	v, ok := r.Content[key]
+	if !ok {
+		return nil, fmt.Errorf("Resp: missing key %q", key)
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Resp: key %q is %T, not an object", key, v)
+	}
+	return m, nil
+}
+
+// GetSlice returns r.Content[key] as a JSON array.
+func (r Resp) GetSlice(key string) ([]interface{}, error) {
+	v, ok := r.Content[key]
+	if !ok {
+		return nil, fmt.Errorf("Resp: missing key %q", key)
+	}
+	s, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Resp: key %q is %T, not an array", key, v)
+	}
+	return s, nil
+}
+
+// GetString returns r.Content[key] as a string.
+func (r Resp) GetString(key string) (string, error) {
+	v, ok := r.Content[key]
+	if !ok {
+		return "", fmt.Errorf("Resp: missing key %q", key)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("Resp: key %q is %T, not a string", key, v)
+	}
+	return s, nil
+}
+
+// GetInt returns r.Content[key] as an int64, whether the API sent it as a
+// JSON number (decoded as json.Number, since Resp.UnmarshalJSON uses
+// UseNumber) or as a quoted numeric string.
+func (r Resp) GetInt(key string) (int64, error) {
+	v, ok := r.Content[key]
+	if !ok {
+		return 0, fmt.Errorf("Resp: missing key %q", key)
+	}
+	n, err := asInt64(v)
+	if err != nil {
+		return 0, fmt.Errorf("Resp: key %q: %v", key, err)
+	}
+	return n, nil
+}
+
+// asInt64 converts a decoded JSON value (json.Number, string, or float64)
+// into an int64, to cope with TTRSS sometimes quoting numeric fields.
+func asInt64(v interface{}) (int64, error) {
+	switch t := v.(type) {
+	case json.Number:
+		return t.Int64()
+	case string:
+		return strconv.ParseInt(t, 10, 64)
+	case float64:
+		return int64(t), nil
+	default:
+		return 0, fmt.Errorf("value %#v is not a number", v)
+	}
 }
 
-// Call issues an API request.
-// If an error status is returned, tt.Error will be set.
-// If an HTTP connection error occurs, returns nil and an error.
+// Call issues an API request with context.Background(). See CallCtx.
 func (tt *Client) Call(op string, body map[string]interface{}) (resp Resp, err error) {
+	return tt.CallCtx(context.Background(), op, body)
+}
+
+// CallCtx issues an API request, honoring ctx's deadline and cancellation
+// in addition to tt.Timeout (if set), and retrying per tt.RetryPolicy.
+// If an error status is returned, resp.Error will be set.
+// If an HTTP connection error occurs, returns a zero Resp and an error.
+func (tt *Client) CallCtx(ctx context.Context, op string, body map[string]interface{}) (resp Resp, err error) {
 	body["op"] = op
 	if tt.SessionID != "" {
 		body["sid"] = tt.SessionID
 	}
+
+	if tt.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tt.Timeout)
+		defer cancel()
+	}
+
+	attempts := tt.RetryPolicy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(tt.RetryPolicy.delay(attempt - 1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				err = ctx.Err()
+				return
+			case <-timer.C:
+			}
+		}
+
+		resp, err = tt.call1(ctx, op, body)
+		if err == nil {
+			return
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			return
+		}
+	}
+	return
+}
+
+// call1 performs a single attempt of the API request described by body,
+// which must already carry "op" and (if applicable) "sid".
+func (tt *Client) call1(ctx context.Context, op string, body map[string]interface{}) (resp Resp, err error) {
 	fmt.Println("### issuing call:", body)
 
 	buffer, err := AsJSONBuffer(body)
@@ -77,13 +301,26 @@ func (tt *Client) Call(op string, body map[string]interface{}) (resp Resp, err e
 		return
 	}
 
-	httpResp, err := tt.Client.Post(tt.ApiEP, "application/json", &buffer)
+	req, err := http.NewRequestWithContext(ctx, "POST", tt.ApiEP, &buffer)
 	if err != nil {
-		err = fmt.Errorf("connection error: %v\n", err)
 		return
 	}
+	req.Header.Set("Content-Type", "application/json")
 
+	httpResp, err := tt.Client.Do(req)
+	if err != nil {
+		err = &retryableError{fmt.Errorf("connection error: %v", err)}
+		return
+	}
 	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode >= 500 {
+		err = &retryableError{fmt.Errorf("server error: HTTP %d", httpResp.StatusCode)}
+		return
+	}
+
+	// Resp.UnmarshalJSON handles number decoding itself (see its doc
+	// comment), so no dec.UseNumber() call is needed here.
 	dec := json.NewDecoder(httpResp.Body)
 	err = dec.Decode(&resp)
 	if err != nil {
@@ -111,9 +348,15 @@ type ConnInfo struct {
 	Password string
 }
 
-// Logs into the host as the designated user.
-// Updates tt.ApiEP and tt.SessionID if successful.
+// Logs into the host as the designated user, with context.Background().
+// See LoginCtx.
 func (tt *Client) Login(conn ConnInfo) (ok bool, err error) {
+	return tt.LoginCtx(context.Background(), conn)
+}
+
+// LoginCtx logs into the host as the designated user.
+// Always updates tt.ApiEP; updates tt.SessionID only if successful.
+func (tt *Client) LoginCtx(ctx context.Context, conn ConnInfo) (ok bool, err error) {
 	apiEP := conn.HostURL
 	if !strings.HasSuffix(apiEP, "/") {
 		apiEP += "/"
@@ -126,7 +369,7 @@ func (tt *Client) Login(conn ConnInfo) (ok bool, err error) {
 		"user":     conn.User,
 		"password": conn.Password,
 	}
-	resp, err := tt.Call("login", loginMap)
+	resp, err := tt.CallCtx(ctx, "login", loginMap)
 	if err != nil {
 		return
 	}
@@ -176,7 +419,7 @@ func (status SubscribeStatus) String() (text string) {
 	case SUB_XML_INVALID:
 		text = "invalid XML at URL"
 	default:
-		fmt.Sprintf("unknown Subscribe status: %d", status)
+		text = fmt.Sprintf("unknown Subscribe status: %d", status)
 	}
 	return
 }
@@ -193,7 +436,13 @@ func (err *SubscribeError) Error() (text string) {
 	return
 }
 
+// Subscribe subscribes to feedURL with context.Background(). See
+// SubscribeCtx.
 func (tt *Client) Subscribe(feedURL string, categoryID int, feedUsername string, feedPassword string) (didSubscribe bool, err error) {
+	return tt.SubscribeCtx(context.Background(), feedURL, categoryID, feedUsername, feedPassword)
+}
+
+func (tt *Client) SubscribeCtx(ctx context.Context, feedURL string, categoryID int, feedUsername string, feedPassword string) (didSubscribe bool, err error) {
 	// An auth'd call that contains a feed URL will always "succeed".
 	// The actual return value is buried in Content["status"] as a map
 	// "code" => int, "message" => string (underlying error).
@@ -205,7 +454,7 @@ func (tt *Client) Subscribe(feedURL string, categoryID int, feedUsername string,
 		subscribeMap["login"] = feedUsername
 		subscribeMap["password"] = feedPassword
 	}
-	resp, err := tt.Call("subscribeToFeed", subscribeMap)
+	resp, err := tt.CallCtx(ctx, "subscribeToFeed", subscribeMap)
 
 	if err != nil {
 		return
@@ -223,38 +472,44 @@ func (tt *Client) Subscribe(feedURL string, categoryID int, feedUsername string,
 		return
 	}
 
-	jsonCode, ok := subscribeStatus["code"].(float64)
+	jsonCode, numErr := asInt64(subscribeStatus["code"])
 	code := SubscribeStatus(jsonCode)
-	if tok := SUB_ADDED <= code && code <= SUB_XML_INVALID; !ok || !tok {
+	if tok := SUB_ALREADY_ADDED <= code && code <= SUB_XML_INVALID; numErr != nil || !tok {
 		err = fmt.Errorf("Unknown SubscribeStatus: %#v",
 			subscribeStatus)
 		return
 	}
 
+	didSubscribe = code == SUB_ADDED || code == SUB_ALREADY_ADDED
+	if didSubscribe {
+		return
+	}
+
 	message, ok := subscribeStatus["message"].(string)
 	if !ok {
 		message = "(no underlying error returned by API)"
 	}
-
 	err = &SubscribeError{code, message}
-
-	didSubscribe = code == SUB_ADDED || code == SUB_ALREADY_ADDED
 	return
 }
 
-const Category = "category"
-const Feed = "feed"
+const TypeCategory = "category"
+const TypeFeed = "feed"
 
 // FeedTreeItem represents an item in the feed tree returned by GetFeedTree.
 type FeedTreeItem struct {
-	ID int `json:"bare_ID"`
+	// TTRSS sends bare_ID as a JSON number or a quoted numeric string
+	// depending on the node, hence jsonx.FlexInt rather than plain int.
+	ID jsonx.FlexInt `json:"bare_ID"`
 	// Name is "/" when it is the synthetic root node.
 	Name string
-	// Type is either Category or Feed.
+	// Type is either TypeCategory or TypeFeed.
 	Type string
 	// LastError is present only if Type == "feed".
 	// If there is no error to report, it will be empty.
 	LastError string `json:"error"`
+	// FeedURL is present only if Type == "feed".
+	FeedURL string `json:"feed_url"`
 	// Items is present only if Type == "category"
 	Items []FeedTreeItem
 }
@@ -265,78 +520,79 @@ type FeedTreeItem struct {
 type WalkFeedTreeFunc func(item *FeedTreeItem) error
 
 func WalkFeedTree(tree *FeedTreeItem, walkFn WalkFeedTreeFunc) error {
-	var err error
-	switch tree.Type {
-	case Category:
-		err = walkFn(tree)
-		if err != nil {
-			return err
+	err := walkFn(tree)
+	if err != nil {
+		if err == filepath.SkipDir {
+			return nil
 		}
+		return err
+	}
 
-		for _, item := range tree.Items {
-			isCat := item.Type == Category
-			err = walkFn(&item)
-			if !isCat && err != filepath.SkipDir {
-				return err
-			}
-			if isCat {
-				err = WalkFeedTree(&item, walkFn)
-				if err != nil {
-					return err
-				}
-			}
-		}
-	case Feed:
-		err = walkFn(tree)
-		if err != nil {
+	if tree.Type != TypeCategory {
+		return nil
+	}
+	for i := range tree.Items {
+		if err := WalkFeedTree(&tree.Items[i], walkFn); err != nil {
 			return err
 		}
 	}
-	return err
+	return nil
 }
 
+// GetFeedTree fetches the feed tree with context.Background(). See
+// GetFeedTreeCtx.
 func (tt *Client) GetFeedTree(includeEmptyCategories bool) (root FeedTreeItem, err error) {
+	return tt.GetFeedTreeCtx(context.Background(), includeEmptyCategories)
+}
+
+func (tt *Client) GetFeedTreeCtx(ctx context.Context, includeEmptyCategories bool) (root FeedTreeItem, err error) {
 	getMap := map[string]interface{} {
 		"include_empty": includeEmptyCategories,
 	}
-	resp, err := tt.Call("getFeedTree", getMap)
+	resp, err := tt.CallCtx(ctx, "getFeedTree", getMap)
 	if err != nil {
 		return
 	}
 
 	if resp.Status != API_STATUS_OK {
-		err = fmt.Errorf("failed to get feed tree: API returned status",
+		err = fmt.Errorf("failed to get feed tree: API returned status %v",
 			resp.Status)
 		return
 	}
 
-	maybeCategories, ok := resp.Content["categories"]
-	if !ok {
-		err = fmt.Errorf("getFeedTree: content lacks categories key")
+	categories, err := resp.GetMap("categories")
+	if err != nil {
+		err = fmt.Errorf("getFeedTree: %v", err)
 		return
 	}
 
-	type jsonObject map[string]interface{}
-	categories, ok := maybeCategories.(jsonObject)
+	maybeItems, ok := categories["items"]
 	if !ok {
-		err = fmt.Errorf("getFeedTree: categories is not a JSON object: %#v",
-			maybeCategories)
+		err = fmt.Errorf("getFeedTree: categories has no items entry")
 		return
 	}
 
-	maybeItems, ok := categories["items"]
-	if (!ok) {
-		err = fmt.Errorf("getFeedTree: categories has no items entry")
+	// json.Decoder decodes JSON arrays as []interface{}, not
+	// []map[string]interface{}, so re-marshal and decode directly into
+	// []FeedTreeItem; encoding/json recurses through the nested Items
+	// field on its own.
+	raw, err := json.Marshal(maybeItems)
+	if err != nil {
+		err = fmt.Errorf("getFeedTree: re-marshalling items: %v", err)
 		return
 	}
 
-	items, ok := maybeItems.([]jsonObject)
-	if !ok {
-		err = fmt.Errorf("getFeedTree: items is not a JSON array: %T", maybeItems)
+	var items []FeedTreeItem
+	if err = json.Unmarshal(raw, &items); err != nil {
+		err = fmt.Errorf("getFeedTree: decoding items: %v", err)
 		return
 	}
 
-	err = fmt.Errorf("BUG: getting there: items %v", items)
+	root = FeedTreeItem{
+		Name:  "/",
+		Type:  TypeCategory,
+		Items: items,
+	}
 	return
 }
 
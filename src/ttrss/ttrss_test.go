@@ -0,0 +1,229 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelayIsWithinBounds(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := policy.delay(attempt)
+		if d <= 0 {
+			t.Fatalf("attempt %d: delay must be positive, got %v", attempt, d)
+		}
+		if d > policy.MaxDelay {
+			t.Errorf("attempt %d: delay %v exceeds MaxDelay %v", attempt, d, policy.MaxDelay)
+		}
+	}
+}
+
+func TestRetryPolicyDelayUsesDefaults(t *testing.T) {
+	var policy RetryPolicy
+	d := policy.delay(1)
+	if d <= 0 || d > 10*time.Second {
+		t.Errorf("delay with zero-value policy out of expected default range: %v", d)
+	}
+}
+
+func TestCallCtxRetriesOnServerError(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"seq":0,"status":0,"content":{"ok":true}}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{
+		ApiEP: srv.URL,
+		RetryPolicy: RetryPolicy{
+			MaxAttempts: 5,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    5 * time.Millisecond,
+		},
+	}
+
+	resp, err := tt.Call("ping", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if resp.Status != API_STATUS_OK {
+		t.Errorf("got status %d, want API_STATUS_OK", resp.Status)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("got %d attempts, want 3", got)
+	}
+}
+
+func TestCallCtxDoesNotRetryAPIError(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Write([]byte(`{"seq":0,"status":1,"content":{"error":"NOT_LOGGED_IN"}}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{
+		ApiEP: srv.URL,
+		RetryPolicy: RetryPolicy{
+			MaxAttempts: 5,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    5 * time.Millisecond,
+		},
+	}
+
+	resp, err := tt.Call("ping", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if resp.Error == nil {
+		t.Errorf("expected resp.Error to be set for API_STATUS_ERR")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("got %d attempts, want 1 (API errors must not be retried)", got)
+	}
+}
+
+func TestCallCtxStopsRetryingWhenContextCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	tt := &Client{
+		ApiEP: srv.URL,
+		RetryPolicy: RetryPolicy{
+			MaxAttempts: 100,
+			BaseDelay:   50 * time.Millisecond,
+			MaxDelay:    50 * time.Millisecond,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	_, err := tt.CallCtx(ctx, "ping", map[string]interface{}{})
+	if err == nil {
+		t.Fatal("expected an error once the context is canceled")
+	}
+}
+
+func sampleFeedTree() FeedTreeItem {
+	return FeedTreeItem{
+		Name: "/",
+		Type: TypeCategory,
+		Items: []FeedTreeItem{
+			{
+				Name: "catA",
+				Type: TypeCategory,
+				Items: []FeedTreeItem{
+					{Name: "feedInA", Type: TypeFeed},
+				},
+			},
+			{Name: "feedAtRoot", Type: TypeFeed},
+		},
+	}
+}
+
+func TestWalkFeedTreeVisitsEachNodeOnce(t *testing.T) {
+	tree := sampleFeedTree()
+	counts := map[string]int{}
+	err := WalkFeedTree(&tree, func(item *FeedTreeItem) error {
+		counts[item.Name]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkFeedTree: %v", err)
+	}
+
+	want := map[string]int{"/": 1, "catA": 1, "feedInA": 1, "feedAtRoot": 1}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("got %d visits to %q, want %d", counts[name], name, n)
+		}
+	}
+}
+
+func TestWalkFeedTreeSkipDirSkipsChildrenNotSiblings(t *testing.T) {
+	tree := sampleFeedTree()
+	var visited []string
+	err := WalkFeedTree(&tree, func(item *FeedTreeItem) error {
+		visited = append(visited, item.Name)
+		if item.Name == "catA" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkFeedTree: %v", err)
+	}
+
+	if contains(visited, "feedInA") {
+		t.Errorf("SkipDir on catA should skip its children, got %v", visited)
+	}
+	if !contains(visited, "feedAtRoot") {
+		t.Errorf("SkipDir on catA should not stop the walk of its siblings, got %v", visited)
+	}
+}
+
+func TestWalkFeedTreePropagatesOtherErrors(t *testing.T) {
+	tree := sampleFeedTree()
+	boom := fmt.Errorf("boom")
+	err := WalkFeedTree(&tree, func(item *FeedTreeItem) error {
+		if item.Name == "catA" {
+			return boom
+		}
+		return nil
+	})
+	if err != boom {
+		t.Errorf("got err %v, want %v", err, boom)
+	}
+}
+
+func contains(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGetFeedTreeDecodesNestedItems(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"seq":0,"status":0,"content":{"categories":{"items":[
+			{"bare_ID":"1","name":"catA","type":"category","items":[
+				{"bare_ID":"2","name":"feedInA","type":"feed","feed_url":"https://example.com/a.xml"}
+			]}
+		]}}}`))
+	}))
+	defer srv.Close()
+
+	tt := &Client{ApiEP: srv.URL}
+	root, err := tt.GetFeedTree(true)
+	if err != nil {
+		t.Fatalf("GetFeedTree: %v", err)
+	}
+	if root.Type != TypeCategory || len(root.Items) != 1 {
+		t.Fatalf("got root %+v", root)
+	}
+
+	catA := root.Items[0]
+	if catA.Name != "catA" || catA.Type != TypeCategory || len(catA.Items) != 1 {
+		t.Fatalf("got catA %+v", catA)
+	}
+	if feed := catA.Items[0]; feed.Name != "feedInA" || feed.FeedURL != "https://example.com/a.xml" {
+		t.Errorf("got feedInA %+v", feed)
+	}
+}